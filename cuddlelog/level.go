@@ -0,0 +1,42 @@
+// Package cuddlelog implements leveled logging and wire-level hex
+// dumping shared by the Cuddlebot command-line tools, replacing the
+// ad-hoc log.Printf/log.Fatal and boolean debug-flag usage they used
+// to rely on.
+package cuddlelog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is a logging severity, ordered from most to least severe.
+type Level int
+
+// Log levels, from most to least severe.
+const (
+	CRITICAL Level = iota
+	ERROR
+	WARNING
+	NOTICE
+	INFO
+	DEBUG
+)
+
+var levelNames = [...]string{"CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+func (l Level) String() string {
+	if l < CRITICAL || l > DEBUG {
+		return "UNKNOWN"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name, case-insensitively.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(name, s) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("cuddlelog: unknown log level %q", s)
+}
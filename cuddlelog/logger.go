@@ -0,0 +1,50 @@
+package cuddlelog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger writes leveled, timestamped messages to an io.Writer,
+// discarding messages less severe than its configured level.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+}
+
+// New returns a Logger that writes messages at level or more severe to
+// out.
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{out: out, level: level}
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.out, "%s %-8s %s\n", time.Now().Format(time.RFC3339), level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Critical(format string, args ...interface{}) { l.log(CRITICAL, format, args...) }
+func (l *Logger) Error(format string, args ...interface{})    { l.log(ERROR, format, args...) }
+func (l *Logger) Warning(format string, args ...interface{})  { l.log(WARNING, format, args...) }
+func (l *Logger) Notice(format string, args ...interface{})   { l.log(NOTICE, format, args...) }
+func (l *Logger) Info(format string, args ...interface{})     { l.log(INFO, format, args...) }
+func (l *Logger) Debug(format string, args ...interface{})    { l.log(DEBUG, format, args...) }
+
+// Fatal logs format/args at CRITICAL, regardless of the configured
+// level, and exits the process.
+func (l *Logger) Fatal(format string, args ...interface{}) {
+	l.mu.Lock()
+	fmt.Fprintf(l.out, "%s %-8s %s\n", time.Now().Format(time.RFC3339), CRITICAL, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+	os.Exit(1)
+}
@@ -0,0 +1,46 @@
+package cuddlelog
+
+import (
+	"io"
+	"os"
+)
+
+// Open builds a Logger from a level name and an optional log file
+// (empty for stderr), and opens the wire-dump file named by wirelog
+// (empty to disable wire-level logging). The returned closer closes
+// any files Open opened and should be deferred by the caller.
+func Open(levelName, logfile, wirelog string) (logger *Logger, wireOut io.Writer, closer func(), err error) {
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	logOut := io.Writer(os.Stderr)
+	if logfile != "" {
+		f, err := os.OpenFile(logfile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		logOut = f
+		closers = append(closers, f)
+	}
+
+	if wirelog != "" {
+		f, err := os.OpenFile(wirelog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			closeAll()
+			return nil, nil, nil, err
+		}
+		wireOut = f
+		closers = append(closers, f)
+	}
+
+	return New(logOut, level), wireOut, closeAll, nil
+}
@@ -0,0 +1,51 @@
+package cuddlelog
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// WireLogger wraps a net.Conn, writing a timestamped hex dump of every
+// byte written to and read from the connection to out. If out is nil,
+// WireLogger logs nothing and simply forwards to the wrapped conn.
+type WireLogger struct {
+	net.Conn
+	out io.Writer
+	mu  sync.Mutex
+}
+
+// NewWireLogger returns conn wrapped to dump its traffic to out.
+func NewWireLogger(conn net.Conn, out io.Writer) *WireLogger {
+	return &WireLogger{Conn: conn, out: out}
+}
+
+func (w *WireLogger) Write(p []byte) (int, error) {
+	n, err := w.Conn.Write(p)
+	w.dump('>', p[:n])
+	return n, err
+}
+
+func (w *WireLogger) Read(p []byte) (int, error) {
+	n, err := w.Conn.Read(p)
+	if n > 0 {
+		w.dump('<', p[:n])
+	}
+	return n, err
+}
+
+// dump writes one hex-dump line: timestamp, direction marker ('>' for
+// written bytes, '<' for read bytes), and the bytes themselves.
+func (w *WireLogger) dump(dir byte, p []byte) {
+	if w.out == nil || len(p) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fmt.Fprintf(w.out, "%s %c %s\n", time.Now().Format(time.RFC3339Nano), dir, hex.EncodeToString(p))
+}
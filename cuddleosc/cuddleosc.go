@@ -0,0 +1,223 @@
+// Command cuddleosc is an OSC-over-UDP control server for the Cuddlebot
+// actuators. It holds a single serial port open and translates incoming
+// Open Sound Control messages into setpoint, PID, and ping commands,
+// making the actuators addressable from live performance tools such as
+// Max/MSP, TouchOSC, or SuperCollider.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+	"github.com/mikepb/go-serial"
+
+	"../cuddlelog"
+	"../msgtype"
+)
+
+// actuators maps the OSC address component to the actuator's address
+// byte.
+var actuators = map[string]uint8{
+	"ribs":  msgtype.ADDR_RIBS,
+	"purr":  msgtype.ADDR_PURR,
+	"spine": msgtype.ADDR_SPINE,
+	"headx": msgtype.ADDR_HEAD_YAW,
+	"heady": msgtype.ADDR_HEAD_PITCH,
+}
+
+func main() {
+	help := flag.Bool("help", false, "print help")
+	portname := flag.String("port", "/dev/ttyUSB0", "the serial port name")
+	listen := flag.String("listen", ":8000", "the OSC listen address")
+	rate := flag.Duration("rate", 0, "minimum interval between messages sent to a single actuator, 0 to disable")
+	loglevel := flag.String("loglevel", "NOTICE", "minimum level to log: CRITICAL, ERROR, WARNING, NOTICE, INFO, or DEBUG")
+	logfile := flag.String("logfile", "", "write log output to this file instead of stderr")
+	wirelog := flag.String("wirelog", "", "hex-dump every byte written to and read from the serial port to this file")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if *help {
+		flag.Usage()
+		os.Exit(0)
+	}
+
+	logger, wireOut, closeLogs, err := cuddlelog.Open(*loglevel, *logfile, *wirelog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer closeLogs()
+
+	// open serial port
+	port, err := serial.Open(*portname, serial.Options{
+		Baudrate: 115200,
+		DataBits: 8,
+		StopBits: 1,
+		Parity:   serial.PARITY_NONE,
+	})
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	defer port.Close()
+
+	conn := net.Conn(cuddlelog.NewWireLogger(net.Conn(port), wireOut))
+	s := newServer(conn, *rate, logger)
+
+	d := osc.NewStandardDispatcher()
+	for name, addr := range actuators {
+		s.register(d, name, addr)
+	}
+
+	logger.Notice("listening for OSC on %s", *listen)
+
+	server := &osc.Server{Addr: *listen, Dispatcher: d}
+	logger.Fatal("%v", server.ListenAndServe())
+}
+
+// server serializes writes to the shared serial port across the OSC
+// handler goroutines and, when rate is nonzero, throttles how often
+// each actuator may be addressed.
+type server struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	rate   time.Duration
+	last   map[uint8]time.Time
+	logger *cuddlelog.Logger
+}
+
+func newServer(conn net.Conn, rate time.Duration, logger *cuddlelog.Logger) *server {
+	return &server{conn: conn, rate: rate, last: make(map[uint8]time.Time), logger: logger}
+}
+
+// throttled reports whether addr was last written to more recently
+// than the configured rate allows.
+func (s *server) throttled(addr uint8) bool {
+	if s.rate <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	if last, ok := s.last[addr]; ok && now.Sub(last) < s.rate {
+		return true
+	}
+	s.last[addr] = now
+	return false
+}
+
+// register adds the setpoint, pid, and ping handlers for the named
+// actuator to d. Bundled messages are scheduled by the osc package
+// according to the bundle's NTP timetag before reaching these handlers.
+func (s *server) register(d *osc.StandardDispatcher, name string, addr uint8) {
+	d.AddMsgHandler(fmt.Sprintf("/cuddle/%s/setpoint", name), func(msg *osc.Message) {
+		if len(msg.Arguments) < 2 {
+			s.logger.Warning("%s: expected setpoint ii arguments", msg.Address)
+			return
+		}
+		duration, ok1 := msg.Arguments[0].(int32)
+		value, ok2 := msg.Arguments[1].(int32)
+		if !ok1 || !ok2 {
+			s.logger.Warning("%s: expected setpoint ii arguments", msg.Address)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.throttled(addr) {
+			return
+		}
+
+		setpoints := []msgtype.Setpoint{{Duration: uint16(duration), Setpoint: uint16(value)}}
+		if err := msgtype.WriteSetpoint(s.conn, addr, 0, 1, setpoints); err != nil {
+			s.logger.Error("%s: %v", msg.Address, err)
+		}
+	})
+
+	d.AddMsgHandler(fmt.Sprintf("/cuddle/%s/pid", name), func(msg *osc.Message) {
+		if len(msg.Arguments) < 3 {
+			s.logger.Warning("%s: expected pid fff arguments", msg.Address)
+			return
+		}
+		kp, ok1 := msg.Arguments[0].(float32)
+		ki, ok2 := msg.Arguments[1].(float32)
+		kd, ok3 := msg.Arguments[2].(float32)
+		if !ok1 || !ok2 || !ok3 {
+			s.logger.Warning("%s: expected pid fff arguments", msg.Address)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.throttled(addr) {
+			return
+		}
+
+		if err := msgtype.WriteSetPID(s.conn, addr, kp, ki, kd); err != nil {
+			s.logger.Error("%s: %v", msg.Address, err)
+		}
+	})
+
+	d.AddMsgHandler(fmt.Sprintf("/cuddle/%s/ping", name), func(msg *osc.Message) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.throttled(addr) {
+			return
+		}
+
+		if err := msgtype.WritePing(s.conn, addr); err != nil {
+			s.logger.Error("%s: %v", msg.Address, err)
+		}
+	})
+}
+
+var header = `Cuddleosc is an OSC-over-UDP control server for the Cuddlebot actuators.
+
+Usage:
+
+    %s [flags]
+
+The flags are:
+
+`
+
+var footer = `
+
+The OSC endpoints are:
+
+    /cuddle/<actuator>/setpoint ii   duration value
+    /cuddle/<actuator>/pid      fff  kp ki kd
+    /cuddle/<actuator>/ping
+
+where <actuator> is one of: ribs, purr, spine, headx, heady.
+
+Messages sent inside an OSC bundle are scheduled for the bundle's NTP
+timetag before being dispatched, so a performance tool can stream
+gestures timed against a shared clock.
+
+Examples:
+
+    $ %s -listen :8000
+
+    $ %s -listen :8000 -rate 20ms
+
+`
+
+func usage() {
+	name := path.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, header, name)
+
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(os.Stderr, "    -%-10s %s\n", f.Name, f.Usage)
+	})
+
+	fmt.Fprintf(os.Stderr, footer, name, name)
+}
@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"os"
 	"path"
@@ -13,10 +12,13 @@ import (
 
 	"github.com/mikepb/go-serial"
 
+	"../cuddlelog"
 	"../msgtype"
 )
 
-var debug = flag.Bool("debug", false, "print debug messages")
+// logger is configured in main from the -loglevel and -logfile flags
+// and used throughout this package in place of the log package.
+var logger *cuddlelog.Logger
 
 func main() {
 	// define actuator flags
@@ -28,131 +30,203 @@ func main() {
 	heady := flag.Bool("heady", false, "send command to head pitch actuator")
 
 	portname := flag.String("port", "/dev/ttyUSB0", "the serial port name")
+	modbus := flag.String("modbus", "", "use Modbus framing instead of the raw protocol: \"rtu\" or \"ascii\"")
+	interactive := flag.Bool("i", false, "start an interactive REPL instead of sending a single command")
+	script := flag.String("script", "", "play back commands from a script file instead of sending a single command")
+	exporter := flag.String("exporter", "", "serve Prometheus metrics on this address instead of sending a single command (e.g. :9187); only supports the raw protocol, not -modbus")
+	exporterInterval := flag.Duration("exporter-interval", 5*time.Second, "how often to poll each actuator's position when -exporter is set")
+	loglevel := flag.String("loglevel", "NOTICE", "minimum level to log: CRITICAL, ERROR, WARNING, NOTICE, INFO, or DEBUG")
+	logfile := flag.String("logfile", "", "write log output to this file instead of stderr")
+	wirelog := flag.String("wirelog", "", "hex-dump every byte written to and read from the serial port to this file")
 
 	// parse flags
 	flag.Usage = usage
 	flag.Parse()
 
-	if flag.NArg() < 2 {
-		fatalUsage()
-	} else if *help {
+	if *help {
 		flag.Usage()
 		os.Exit(0)
+	} else if !*interactive && *script == "" && *exporter == "" && flag.NArg() < 1 {
+		fatalUsage()
+	}
+
+	var wireOut io.Writer
+	var closeLogs func()
+	var err error
+	logger, wireOut, closeLogs, err = cuddlelog.Open(*loglevel, *logfile, *wirelog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	defer closeLogs()
+
+	const baudrate = 115200
 
 	// open serial port
 	port, err := serial.Open(*portname, serial.Options{
-		Baudrate: 115200,
+		Baudrate: baudrate,
 		DataBits: 8,
 		StopBits: 1,
 		Parity:   serial.PARITY_NONE,
 	})
 	if err != nil {
-		log.Fatal(err)
-	} else if *debug {
-		log.Printf("opened %s", *portname)
+		logger.Fatal("%v", err)
 	}
+	logger.Debug("opened %s", *portname)
 	defer port.Close()
 
-	// net wrapper
-	conn := net.Conn(port)
+	// net wrapper, with wire-level hex dumping if -wirelog is set
+	conn := net.Conn(cuddlelog.NewWireLogger(net.Conn(port), wireOut))
+
+	// framing
+	var rw io.ReadWriter
+	switch *modbus {
+	case "":
+		rw = conn
+	case "rtu":
+		rw = msgtype.NewModbusRTUWriter(conn, baudrate)
+	case "ascii":
+		rw = msgtype.NewModbusASCIIWriter(conn)
+	default:
+		logger.Fatal("unknown -modbus mode %q", *modbus)
+	}
+
+	// pick the target actuators
+	selected := selectedActuators(*ribs, *purr, *spine, *headx, *heady)
+	if len(selected) == 0 {
+		fatalUsage()
+	}
+
+	if *exporter != "" && *modbus != "" {
+		logger.Fatal("-exporter only supports the raw protocol; remove -modbus")
+	}
 
 	// run command
-	switch true {
-	case *ribs:
-		runcmd(conn, msgtype.ADDR_RIBS)
-	case *purr:
-		runcmd(conn, msgtype.ADDR_PURR)
-	case *spine:
-		runcmd(conn, msgtype.ADDR_SPINE)
-	case *headx:
-		runcmd(conn, msgtype.ADDR_HEAD_YAW)
-	case *heady:
-		runcmd(conn, msgtype.ADDR_HEAD_PITCH)
+	switch {
+	case *exporter != "":
+		runExporter(conn, rw, selected, *exporterInterval, *exporter)
+	case *script != "":
+		if err := runscript(conn, rw, selected[0].addr, *script); err != nil {
+			logger.Fatal("%v", err)
+		}
+	case *interactive:
+		repl(conn, rw, selected[0].addr)
+	default:
+		if err := runcmd(conn, rw, selected[0].addr, flag.Arg(0), flag.Args()[1:]); err != nil {
+			fatalUsage()
+		}
 	}
 }
 
-func runcmd(conn net.Conn, addr uint8) {
-	// run command
-	switch flag.Arg(1) {
+// actuator names a single actuator's command-line flag and protocol
+// address together.
+type actuator struct {
+	name string
+	addr uint8
+}
+
+// selectedActuators returns the actuators whose flags are set, in
+// flag-declaration order.
+func selectedActuators(ribs, purr, spine, headx, heady bool) []actuator {
+	var selected []actuator
+	if ribs {
+		selected = append(selected, actuator{"ribs", msgtype.ADDR_RIBS})
+	}
+	if purr {
+		selected = append(selected, actuator{"purr", msgtype.ADDR_PURR})
+	}
+	if spine {
+		selected = append(selected, actuator{"spine", msgtype.ADDR_SPINE})
+	}
+	if headx {
+		selected = append(selected, actuator{"headx", msgtype.ADDR_HEAD_YAW})
+	}
+	if heady {
+		selected = append(selected, actuator{"heady", msgtype.ADDR_HEAD_PITCH})
+	}
+	return selected
+}
+
+// runcmd dispatches the named command with the given arguments to the
+// actuator at addr. It is shared by the one-shot CLI mode, the
+// interactive REPL, and script playback, so it reports errors rather
+// than exiting the process.
+func runcmd(conn net.Conn, rw io.ReadWriter, addr uint8, cmd string, args []string) error {
+	switch cmd {
 	case "setpid":
-		if flag.NArg() < 5 {
-			fatalUsage()
+		if len(args) < 3 {
+			return fmt.Errorf("setpid requires kp, ki, and kd")
 		}
 
 		var kp, ki, kd float32
-		fmt.Fscanf(bytes.NewBufferString(flag.Arg(2)), "%f", &kp)
-		fmt.Fscanf(bytes.NewBufferString(flag.Arg(3)), "%f", &ki)
-		fmt.Fscanf(bytes.NewBufferString(flag.Arg(4)), "%f", &kd)
+		fmt.Fscanf(bytes.NewBufferString(args[0]), "%f", &kp)
+		fmt.Fscanf(bytes.NewBufferString(args[1]), "%f", &ki)
+		fmt.Fscanf(bytes.NewBufferString(args[2]), "%f", &kd)
 
-		if *debug {
-			log.Printf("parsed pid kp=%f ki=%f kd=%f", kp, ki, kd)
-		}
+		logger.Debug("parsed pid kp=%f ki=%f kd=%f", kp, ki, kd)
 
-		msgtype.WriteSetPID(conn, addr, kp, ki, kd)
+		msgtype.WriteSetPID(rw, addr, kp, ki, kd)
 
 	case "setpoint":
-		if flag.NArg() < 6 {
-			fatalUsage()
-		}
-
-		if flag.NArg()%2 != 0 {
-			log.Fatal(os.Stderr, "Error: duration and setpoint must be given in pairs")
+		if len(args) < 4 {
+			return fmt.Errorf("setpoint requires delay, loop, and at least one duration/setpoint pair")
+		} else if len(args)%2 != 0 {
+			return fmt.Errorf("duration and setpoint must be given in pairs")
 		}
 
 		var delayInt, loopInt int
 
-		fmt.Fscanf(bytes.NewBufferString(flag.Arg(2)), "%d", &delayInt)
-		fmt.Fscanf(bytes.NewBufferString(flag.Arg(3)), "%d", &loopInt)
+		fmt.Fscanf(bytes.NewBufferString(args[0]), "%d", &delayInt)
+		fmt.Fscanf(bytes.NewBufferString(args[1]), "%d", &loopInt)
 
 		if delayInt < 0 || loopInt < 0 {
-			log.Fatal(os.Stderr, "Error: delay and loop must be positive")
+			return fmt.Errorf("delay and loop must be positive")
 		}
 
 		delay := uint16(delayInt)
 		loop := uint16(loopInt)
 
-		setpoints := make([]msgtype.Setpoint, (flag.NArg()-4)/2)
-		for i := 4; i < flag.NArg(); i += 2 {
+		setpoints := make([]msgtype.Setpoint, (len(args)-2)/2)
+		for i := 2; i < len(args); i += 2 {
 			var duration, setpoint int
 
-			fmt.Fscanf(bytes.NewBufferString(flag.Arg(i)), "%d", &duration)
-			fmt.Fscanf(bytes.NewBufferString(flag.Arg(i+1)), "%d", &setpoint)
+			fmt.Fscanf(bytes.NewBufferString(args[i]), "%d", &duration)
+			fmt.Fscanf(bytes.NewBufferString(args[i+1]), "%d", &setpoint)
 
-			if delayInt < 0 || loopInt < 0 {
-				log.Fatal(os.Stderr, "Error: duration and setpoint must be positive")
+			if duration < 0 || setpoint < 0 {
+				return fmt.Errorf("duration and setpoint must be positive")
 			}
 
-			j := (i - 4) / 2
+			j := (i - 2) / 2
 
 			setpoints[j].Duration = uint16(duration)
 			setpoints[j].Setpoint = uint16(setpoint)
 		}
 
-		msgtype.WriteSetpoint(conn, addr, delay, loop, setpoints)
+		msgtype.WriteSetpoint(rw, addr, delay, loop, setpoints)
 
 	case "ping":
-		msgtype.WritePing(conn, addr)
+		msgtype.WritePing(rw, addr)
 		conn.SetReadDeadline(time.Now().Add(time.Second))
-		io.Copy(os.Stdout, conn)
+		io.Copy(os.Stdout, rw)
 
 	case "test":
-		msgtype.WriteRunTests(conn, addr)
+		msgtype.WriteRunTests(rw, addr)
 		conn.SetReadDeadline(time.Now().Add(time.Minute * 5))
-		io.Copy(os.Stdout, conn)
+		io.Copy(os.Stdout, rw)
 
 	case "value":
-		msgtype.WriteRequestPosition(conn, addr)
+		msgtype.WriteRequestPosition(rw, addr)
 		conn.SetReadDeadline(time.Now().Add(time.Second))
-		io.Copy(os.Stdout, conn)
+		io.Copy(os.Stdout, rw)
 
 	default:
-		fatalUsage()
+		return fmt.Errorf("unknown command %q", cmd)
 	}
 
-	if *debug {
-		log.Printf("sent %s message to address %d", flag.Arg(1), addr)
-	}
+	logger.Debug("sent %s message to address %d", cmd, addr)
+
+	return nil
 }
 
 var header = `Cuddlespeak is a tool for testing the Cuddlebot actuators.
@@ -174,6 +248,10 @@ The commands are:
     ping        send a ping
     test        send test command
     value       read motor position
+    watch value [interval]
+                poll the motor position every interval (milliseconds,
+                default 200) and render it as a live sparkline; only
+                available in -i mode
 
 The setpid command accepts these arguments:
 
@@ -206,6 +284,16 @@ Examples:
     $ %s -ribs value
     0.1
 
+    $ %s -ribs -i
+    cuddle> setpid 40.4 1.0 -1.0
+    cuddle> watch value
+
+    $ %s -ribs -script gesture.cuddle
+
+    $ %s -ribs -purr -exporter :9187 -exporter-interval 1s
+
+    $ %s -ribs -loglevel DEBUG -wirelog wire.log ping
+
 `
 
 func usage() {
@@ -216,7 +304,7 @@ func usage() {
 		fmt.Fprintf(os.Stderr, "    -%-10s %s\n", f.Name, f.Usage)
 	})
 
-	fmt.Fprintf(os.Stderr, footer, name, name, name, name, name)
+	fmt.Fprintf(os.Stderr, footer, name, name, name, name, name, name, name, name, name)
 }
 
 func fatalUsage() {
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"../msgtype"
+)
+
+var (
+	positionGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cuddlebot_position",
+		Help: "Last motor position reported by the actuator, in turns.",
+	}, []string{"actuator"})
+
+	roundtripsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cuddlebot_roundtrips_total",
+		Help: "Completed command round-trips, by actuator.",
+	}, []string{"actuator"})
+
+	timeoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cuddlebot_timeouts_total",
+		Help: "Command round-trips that timed out waiting for a reply, by actuator.",
+	}, []string{"actuator"})
+
+	parseErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cuddlebot_parse_errors_total",
+		Help: "Replies that failed to parse or verify, by actuator.",
+	}, []string{"actuator"})
+)
+
+func init() {
+	prometheus.MustRegister(positionGauge, roundtripsTotal, timeoutsTotal, parseErrorsTotal)
+}
+
+// runExporter periodically polls each actuator's position over rw and
+// serves the results as Prometheus metrics on addr until the process
+// is killed.
+func runExporter(conn net.Conn, rw io.ReadWriter, actuators []actuator, interval time.Duration, addr string) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for _, a := range actuators {
+				pollPosition(conn, rw, a, interval)
+			}
+		}
+	}()
+
+	http.Handle("/metrics", promhttp.Handler())
+	logger.Notice("serving Prometheus metrics on %s every %s", addr, interval)
+	logger.Fatal("%v", http.ListenAndServe(addr, nil))
+}
+
+// pollPosition requests a's position and records the result in the
+// exporter's gauges and counters. It parses the reply as the raw
+// protocol's text encoding; the caller must not combine -exporter
+// with -modbus, whose writers return decoded binary payloads.
+func pollPosition(conn net.Conn, rw io.ReadWriter, a actuator, timeout time.Duration) {
+	msgtype.WriteRequestPosition(rw, a.addr)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	value, err := msgtype.ReadPosition(rw)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		timeoutsTotal.WithLabelValues(a.name).Inc()
+		return
+	} else if err != nil {
+		parseErrorsTotal.WithLabelValues(a.name).Inc()
+		return
+	}
+
+	positionGauge.WithLabelValues(a.name).Set(float64(value))
+	roundtripsTotal.WithLabelValues(a.name).Inc()
+}
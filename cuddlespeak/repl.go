@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+
+	"../msgtype"
+)
+
+// repl runs an interactive command loop against the actuator at addr,
+// reading lines from stdin with history and tab completion instead of
+// reopening the serial port for every command.
+func repl(conn net.Conn, rw io.ReadWriter, addr uint8) {
+	completer := readline.NewPrefixCompleter(
+		readline.PcItem("setpid"),
+		readline.PcItem("setpoint"),
+		readline.PcItem("ping"),
+		readline.PcItem("test"),
+		readline.PcItem("value"),
+		readline.PcItem("watch",
+			readline.PcItem("value"),
+		),
+	)
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "cuddle> ",
+		HistoryFile:     filepath.Join(os.TempDir(), ".cuddlespeak_history"),
+		AutoComplete:    completer,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		} else if err == io.EOF {
+			return
+		} else if err != nil {
+			logger.Fatal("%v", err)
+		}
+
+		if err := dispatch(conn, rw, addr, line); err != nil {
+			logger.Error("%v", err)
+		}
+	}
+}
+
+// dispatch parses a single REPL line and runs it, either as the watch
+// command or as a command handled by runcmd.
+func dispatch(conn net.Conn, rw io.ReadWriter, addr uint8, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if fields[0] == "watch" {
+		return watch(conn, rw, addr, fields[1:])
+	}
+
+	return runcmd(conn, rw, addr, fields[0], fields[1:])
+}
+
+// watch polls the motor position at the given interval (in
+// milliseconds, fields[1], default 200ms) and renders it as a live
+// sparkline until interrupted with Ctrl-C.
+func watch(conn net.Conn, rw io.ReadWriter, addr uint8, fields []string) error {
+	if len(fields) < 1 || fields[0] != "value" {
+		return fmt.Errorf("usage: watch value [interval]")
+	}
+
+	interval := 200 * time.Millisecond
+	if len(fields) > 1 {
+		ms, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("watch: %v", err)
+		}
+		interval = time.Duration(ms) * time.Millisecond
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	defer signal.Stop(sigc)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var history []float64
+
+	for {
+		select {
+		case <-sigc:
+			fmt.Println()
+			return nil
+
+		case <-ticker.C:
+			msgtype.WriteRequestPosition(rw, addr)
+			conn.SetReadDeadline(time.Now().Add(interval))
+
+			buf := make([]byte, 64)
+			n, err := rw.Read(buf)
+			if err != nil {
+				continue
+			}
+
+			var value float64
+			if _, err := fmt.Sscanf(string(buf[:n]), "%f", &value); err != nil {
+				continue
+			}
+
+			history = append(history, value)
+			if len(history) > sparklineWidth {
+				history = history[len(history)-sparklineWidth:]
+			}
+
+			fmt.Printf("\r%s %6.3f", sparkline(history), value)
+		}
+	}
+}
+
+// sparklineWidth is the number of samples shown in a watch sparkline.
+const sparklineWidth = 40
+
+var sparkChars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders values as a single line of block characters scaled
+// to their own min/max.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+
+	out := make([]rune, len(values))
+	for i, v := range values {
+		idx := 0
+		if span := max - min; span > 0 {
+			idx = int((v - min) / span * float64(len(sparkChars)-1))
+		}
+		out[i] = sparkChars[idx]
+	}
+
+	return string(out)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runscript plays back the commands in path against the actuator at
+// addr. Each non-blank line not starting with '#' is a command as
+// accepted by runcmd, with two directives on top: "sleep ms" pauses
+// playback, and "@label" / "goto label" implement simple loops. Lines
+// starting with '#' are comments and are skipped.
+func runscript(conn net.Conn, rw io.ReadWriter, addr uint8, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	labels := make(map[string]int)
+	for i, line := range lines {
+		if label := strings.TrimSpace(line); strings.HasPrefix(label, "@") {
+			labels[strings.TrimPrefix(label, "@")] = i
+		}
+	}
+
+	for pc := 0; pc < len(lines); pc++ {
+		line := strings.TrimSpace(lines[pc])
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+		case "sleep":
+			if len(fields) < 2 {
+				return fmt.Errorf("%s:%d: sleep requires a duration in milliseconds", path, pc+1)
+			}
+			ms, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", path, pc+1, err)
+			}
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+
+		case "goto":
+			if len(fields) < 2 {
+				return fmt.Errorf("%s:%d: goto requires a label", path, pc+1)
+			}
+			target, ok := labels[fields[1]]
+			if !ok {
+				return fmt.Errorf("%s:%d: unknown label %q", path, pc+1, fields[1])
+			}
+			pc = target
+
+		default:
+			if err := runcmd(conn, rw, addr, fields[0], fields[1:]); err != nil {
+				return fmt.Errorf("%s:%d: %v", path, pc+1, err)
+			}
+		}
+	}
+
+	return nil
+}
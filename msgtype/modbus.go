@@ -0,0 +1,150 @@
+package msgtype
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ASCII frame size bounds, in characters, counting the ':' start but
+// not the "\r\n" end delimiter: at minimum ':' and one hex-encoded byte
+// (the LRC), at most ':' and 256 hex-encoded bytes (address, command,
+// payload, and LRC together).
+const (
+	asciiMinFrame = 3
+	asciiMaxFrame = 513
+)
+
+// ModbusRTUWriter wraps a net.Conn, framing each message written to it
+// as a Modbus RTU frame: the address and command bytes written by the
+// Write* functions, followed by a CRC16 checksum. Frames are separated
+// by at least 3.5 character times of silence, as required by the RTU
+// spec to let slaves detect frame boundaries without a delimiter.
+type ModbusRTUWriter struct {
+	conn      net.Conn
+	charDelay time.Duration
+	lastWrite time.Time
+}
+
+// NewModbusRTUWriter returns a ModbusRTUWriter wrapping conn. baud is
+// the serial port's configured baud rate, used to size the inter-frame
+// silence.
+func NewModbusRTUWriter(conn net.Conn, baud int) *ModbusRTUWriter {
+	// 11 bit times per character: start bit, 8 data bits, parity or a
+	// second stop bit, stop bit. On an 8N1 port a character is 10 bits,
+	// so there the silence is slightly longer than 3.5 characters.
+	charDelay := time.Second * 11 / time.Duration(baud)
+	return &ModbusRTUWriter{conn: conn, charDelay: charDelay}
+}
+
+func (w *ModbusRTUWriter) Write(p []byte) (int, error) {
+	if silence := w.charDelay*7/2 - time.Since(w.lastWrite); silence > 0 {
+		time.Sleep(silence)
+	}
+
+	frame := make([]byte, 0, len(p)+2)
+	frame = append(frame, p...)
+	frame = append(frame, crc16(p)...)
+
+	_, err := w.conn.Write(frame)
+	w.lastWrite = time.Now()
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *ModbusRTUWriter) Read(p []byte) (int, error) {
+	return w.conn.Read(p)
+}
+
+// crc16 computes the Modbus RTU CRC16 checksum, little-endian encoded.
+func crc16(data []byte) []byte {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return []byte{byte(crc), byte(crc >> 8)}
+}
+
+// ModbusASCIIWriter wraps a net.Conn, framing each message written to
+// it as a Modbus ASCII frame: a ':' start character, the hex-encoded
+// address, command, and payload bytes, a hex-encoded LRC checksum, and
+// a "\r\n" end delimiter.
+type ModbusASCIIWriter struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewModbusASCIIWriter returns a ModbusASCIIWriter wrapping conn.
+func NewModbusASCIIWriter(conn net.Conn) *ModbusASCIIWriter {
+	// +2 for the "\r\n" end delimiter, which is not counted by asciiMaxFrame.
+	return &ModbusASCIIWriter{conn: conn, r: bufio.NewReaderSize(conn, asciiMaxFrame+2)}
+}
+
+func (w *ModbusASCIIWriter) Write(p []byte) (int, error) {
+	body := hex.EncodeToString(append(append([]byte{}, p...), lrc(p)))
+
+	frame := make([]byte, 0, len(body)+3)
+	frame = append(frame, ':')
+	frame = append(frame, []byte(body)...)
+	frame = append(frame, '\r', '\n')
+
+	if _, err := w.conn.Write(frame); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Read reads one Modbus ASCII frame, delimited by "\r\n", from the
+// underlying conn, verifying its LRC before returning the decoded
+// address, command, and payload bytes (without the LRC).
+func (w *ModbusASCIIWriter) Read(p []byte) (int, error) {
+	line, err := w.r.ReadBytes('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	frame := bytes.TrimRight(line, "\r\n")
+	if len(frame) < asciiMinFrame || len(frame) > asciiMaxFrame {
+		return 0, fmt.Errorf("msgtype: ascii frame length %d out of range", len(frame))
+	}
+	if frame[0] != ':' {
+		return 0, fmt.Errorf("msgtype: malformed ascii frame")
+	}
+
+	decoded, err := hex.DecodeString(string(frame[1:]))
+	if err != nil {
+		return 0, err
+	}
+	if len(decoded) < 1 {
+		return 0, fmt.Errorf("msgtype: ascii frame missing LRC")
+	}
+
+	payload, checksum := decoded[:len(decoded)-1], decoded[len(decoded)-1]
+	if lrc(payload) != checksum {
+		return 0, fmt.Errorf("msgtype: ascii LRC mismatch")
+	}
+
+	return copy(p, payload), nil
+}
+
+// lrc computes the Modbus ASCII longitudinal redundancy check: the
+// two's complement of the sum of the given bytes.
+func lrc(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return ^sum + 1
+}
@@ -0,0 +1,93 @@
+// Package msgtype implements message framing for Cuddlebot actuator
+// commands. Messages are built by the Write* functions below and handed
+// to an io.Writer for transport: a plain net.Conn sends them using the
+// raw serial protocol (address byte, command byte, payload), while
+// ModbusRTUWriter and ModbusASCIIWriter add Modbus-compatible framing
+// around the same bytes.
+package msgtype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Actuator addresses, used as both the raw protocol address byte and
+// the Modbus slave id.
+const (
+	ADDR_RIBS uint8 = iota + 1
+	ADDR_PURR
+	ADDR_SPINE
+	ADDR_HEAD_YAW
+	ADDR_HEAD_PITCH
+)
+
+// Command codes identifying the message kind.
+const (
+	cmdSetPID          byte = 0x01
+	cmdSetpoint        byte = 0x02
+	cmdPing            byte = 0x03
+	cmdRunTests        byte = 0x04
+	cmdRequestPosition byte = 0x05
+)
+
+// Setpoint is a single duration/setpoint pair in a motion sequence.
+type Setpoint struct {
+	Duration uint16
+	Setpoint uint16
+}
+
+// writeFrame writes the address byte, command byte, and payload to w.
+func writeFrame(w io.Writer, addr uint8, cmd byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+2)
+	frame = append(frame, addr, cmd)
+	frame = append(frame, payload...)
+	_, err := w.Write(frame)
+	return err
+}
+
+// WriteSetPID writes a setpid message with the given PID coefficients.
+func WriteSetPID(w io.Writer, addr uint8, kp, ki, kd float32) error {
+	payload := new(bytes.Buffer)
+	binary.Write(payload, binary.BigEndian, kp)
+	binary.Write(payload, binary.BigEndian, ki)
+	binary.Write(payload, binary.BigEndian, kd)
+	return writeFrame(w, addr, cmdSetPID, payload.Bytes())
+}
+
+// WriteSetpoint writes a setpoint message describing a motion sequence.
+func WriteSetpoint(w io.Writer, addr uint8, delay, loop uint16, setpoints []Setpoint) error {
+	payload := new(bytes.Buffer)
+	binary.Write(payload, binary.BigEndian, delay)
+	binary.Write(payload, binary.BigEndian, loop)
+	for _, sp := range setpoints {
+		binary.Write(payload, binary.BigEndian, sp.Duration)
+		binary.Write(payload, binary.BigEndian, sp.Setpoint)
+	}
+	return writeFrame(w, addr, cmdSetpoint, payload.Bytes())
+}
+
+// WritePing writes a ping message.
+func WritePing(w io.Writer, addr uint8) error {
+	return writeFrame(w, addr, cmdPing, nil)
+}
+
+// WriteRunTests writes a message requesting the actuator's self test.
+func WriteRunTests(w io.Writer, addr uint8) error {
+	return writeFrame(w, addr, cmdRunTests, nil)
+}
+
+// WriteRequestPosition writes a message requesting the actuator's
+// current motor position.
+func WriteRequestPosition(w io.Writer, addr uint8) error {
+	return writeFrame(w, addr, cmdRequestPosition, nil)
+}
+
+// ReadPosition reads and parses the motor position reply sent in
+// response to WriteRequestPosition.
+func ReadPosition(r io.Reader) (float32, error) {
+	var value float32
+	_, err := fmt.Fscanf(r, "%f", &value)
+	return value, err
+}